@@ -30,7 +30,17 @@ var (
 	ErrMalformedElement = errors.New("element is malformed")
 )
 
-type EntityLocator []TagLocator
+// EntityLocator turns raw tag families into an entity key and, from that
+// key, a shard id. TagLocators lists every tag that makes up the entity key
+// in order; PrefixLen, when non-zero, restricts shard selection to the
+// first PrefixLen locators so that related series (e.g. all endpoints of one
+// service) land on the same shard while the full entity key still
+// disambiguates them. Strategy defaults to ShardStrategyMurmur3 when nil.
+type EntityLocator struct {
+	Strategy    ShardStrategy
+	TagLocators []TagLocator
+	PrefixLen   int
+}
 
 type TagLocator struct {
 	FamilyOffset int
@@ -38,8 +48,8 @@ type TagLocator struct {
 }
 
 func (e EntityLocator) Find(value []*modelv2.TagFamilyForWrite) (tsdb.Entity, error) {
-	entity := make(tsdb.Entity, len(e))
-	for i, index := range e {
+	entity := make(tsdb.Entity, len(e.TagLocators))
+	for i, index := range e.TagLocators {
 		tag, err := GetTagByOffset(value, index.FamilyOffset, index.TagOffset)
 		if err != nil {
 			return nil, err
@@ -58,11 +68,25 @@ func (e EntityLocator) Locate(value []*modelv2.TagFamilyForWrite, shardNum uint3
 	if err != nil {
 		return nil, 0, err
 	}
-	id, err := ShardID(entity.Marshal(), shardNum)
+	strategy := e.Strategy
+	if strategy == nil {
+		strategy = murmur3Strategy{}
+	}
+	id, err := strategy.Shard(e.shardKey(entity), shardNum)
 	if err != nil {
 		return nil, 0, err
 	}
-	return entity, common.ShardID(id), nil
+	return entity, id, nil
+}
+
+// shardKey returns the portion of the marshaled entity that participates in
+// shard selection. With PrefixLen unset (or covering the whole entity) this
+// is the full entity key, matching the previous behavior.
+func (e EntityLocator) shardKey(entity tsdb.Entity) []byte {
+	if e.PrefixLen <= 0 || e.PrefixLen >= len(entity) {
+		return entity.Marshal()
+	}
+	return entity[:e.PrefixLen].Marshal()
 }
 
 func GetTagByOffset(value []*modelv2.TagFamilyForWrite, fIndex, tIndex int) (*modelv2.TagValue, error) {
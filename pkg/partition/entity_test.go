@@ -0,0 +1,120 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package partition
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	modelv2 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v2"
+)
+
+// recordingStrategy is a test-only ShardStrategy that records the exact key
+// bytes it was asked to shard, instead of hashing them, so PrefixLen
+// truncation can be asserted on the key content directly rather than
+// inferred from hash output.
+type recordingStrategy struct {
+	keys [][]byte
+}
+
+func (r *recordingStrategy) Shard(key []byte, _ uint32) (common.ShardID, error) {
+	r.keys = append(r.keys, append([]byte(nil), key...))
+	return common.ShardID(len(r.keys) - 1), nil
+}
+
+func strTagValue(v string) *modelv2.TagValue {
+	return &modelv2.TagValue{Value: &modelv2.TagValue_Str{Str: &modelv2.Str{Value: v}}}
+}
+
+func entityTagFamilies(values ...string) []*modelv2.TagFamilyForWrite {
+	tags := make([]*modelv2.TagValue, len(values))
+	for i, v := range values {
+		tags[i] = strTagValue(v)
+	}
+	return []*modelv2.TagFamilyForWrite{{Tags: tags}}
+}
+
+func twoTagLocator(strategy ShardStrategy, prefixLen int) EntityLocator {
+	return EntityLocator{
+		Strategy:    strategy,
+		TagLocators: []TagLocator{{FamilyOffset: 0, TagOffset: 0}, {FamilyOffset: 0, TagOffset: 1}},
+		PrefixLen:   prefixLen,
+	}
+}
+
+func TestEntityLocator_Locate_PrefixModeGroupsByLeadingTags(t *testing.T) {
+	strategy := &recordingStrategy{}
+	locator := twoTagLocator(strategy, 1)
+
+	if _, _, err := locator.Locate(entityTagFamilies("svc-a", "ep-1"), 16); err != nil {
+		t.Fatalf("Locate() error = %v", err)
+	}
+	if _, _, err := locator.Locate(entityTagFamilies("svc-a", "ep-2"), 16); err != nil {
+		t.Fatalf("Locate() error = %v", err)
+	}
+	if _, _, err := locator.Locate(entityTagFamilies("svc-b", "ep-1"), 16); err != nil {
+		t.Fatalf("Locate() error = %v", err)
+	}
+
+	if len(strategy.keys) != 3 {
+		t.Fatalf("recorded %d shard keys, want 3", len(strategy.keys))
+	}
+	if !bytes.Equal(strategy.keys[0], strategy.keys[1]) {
+		t.Fatalf("entities sharing prefix %q hashed different keys: %x vs %x", "svc-a", strategy.keys[0], strategy.keys[1])
+	}
+	if bytes.Equal(strategy.keys[0], strategy.keys[2]) {
+		t.Fatalf("entities with different prefixes (%q vs %q) hashed the same key", "svc-a", "svc-b")
+	}
+}
+
+func TestEntityLocator_Locate_NoPrefixUsesFullEntity(t *testing.T) {
+	strategy := &recordingStrategy{}
+	locator := twoTagLocator(strategy, 0)
+
+	if _, _, err := locator.Locate(entityTagFamilies("svc-a", "ep-1"), 16); err != nil {
+		t.Fatalf("Locate() error = %v", err)
+	}
+	if _, _, err := locator.Locate(entityTagFamilies("svc-a", "ep-2"), 16); err != nil {
+		t.Fatalf("Locate() error = %v", err)
+	}
+
+	if bytes.Equal(strategy.keys[0], strategy.keys[1]) {
+		t.Fatal("differing tail tag produced the same shard key with PrefixLen unset")
+	}
+}
+
+func TestEntityLocator_Find_ReturnsFullEntityRegardlessOfPrefixLen(t *testing.T) {
+	locator := twoTagLocator(&recordingStrategy{}, 1)
+
+	entity, err := locator.Find(entityTagFamilies("svc-a", "ep-1"))
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if len(entity) != len(locator.TagLocators) {
+		t.Fatalf("Find() returned %d components, want %d (PrefixLen must not truncate Find)", len(entity), len(locator.TagLocators))
+	}
+
+	locatedEntity, _, err := locator.Locate(entityTagFamilies("svc-a", "ep-1"), 16)
+	if err != nil {
+		t.Fatalf("Locate() error = %v", err)
+	}
+	if len(locatedEntity) != len(locator.TagLocators) {
+		t.Fatalf("Locate() returned entity with %d components, want %d", len(locatedEntity), len(locator.TagLocators))
+	}
+}
@@ -0,0 +1,150 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package partition
+
+import (
+	"github.com/cespare/xxhash/v2"
+	"github.com/pkg/errors"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+)
+
+// ShardStrategyType identifies one of the hash strategies an EntityLocator
+// can use to turn an entity key into a shard id.
+type ShardStrategyType int
+
+const (
+	// ShardStrategyMurmur3 hashes the whole entity key with murmur3, the
+	// strategy ShardID has always used. It gives no locality guarantees:
+	// resharding moves close to (N-1)/N of the keys.
+	ShardStrategyMurmur3 ShardStrategyType = iota
+	// ShardStrategyXXHash hashes the entity key with xxhash, a cheaper
+	// alternative to murmur3 with similar distribution properties.
+	ShardStrategyXXHash
+	// ShardStrategyRendezvous picks, for every shard, argmax(hash(shardID
+	// || entityKey)) (highest random weight). Only 1/N of the keys move
+	// when a shard is added or removed, at the cost of an O(shardNum) scan
+	// per lookup.
+	ShardStrategyRendezvous
+	// ShardStrategyJumpHash uses Lamping & Veach's jump consistent hash.
+	// Like rendezvous it only moves 1/N of the keys on resize, but the
+	// lookup is O(log shardNum) and it needs no per-shard state.
+	ShardStrategyJumpHash
+)
+
+func (t ShardStrategyType) String() string {
+	switch t {
+	case ShardStrategyMurmur3:
+		return "murmur3"
+	case ShardStrategyXXHash:
+		return "xxhash"
+	case ShardStrategyRendezvous:
+		return "rendezvous"
+	case ShardStrategyJumpHash:
+		return "jump-hash"
+	default:
+		return "unknown"
+	}
+}
+
+// ErrUnknownShardStrategy is returned by NewShardStrategy for an
+// unrecognized ShardStrategyType.
+var ErrUnknownShardStrategy = errors.New("unknown shard strategy")
+
+// ShardStrategy maps a marshaled entity key to a shard id among shardNum
+// shards.
+type ShardStrategy interface {
+	Shard(key []byte, shardNum uint32) (common.ShardID, error)
+}
+
+// NewShardStrategy builds the ShardStrategy identified by t.
+func NewShardStrategy(t ShardStrategyType) (ShardStrategy, error) {
+	switch t {
+	case ShardStrategyMurmur3:
+		return murmur3Strategy{}, nil
+	case ShardStrategyXXHash:
+		return xxhashStrategy{}, nil
+	case ShardStrategyRendezvous:
+		return rendezvousStrategy{}, nil
+	case ShardStrategyJumpHash:
+		return jumpHashStrategy{}, nil
+	default:
+		return nil, errors.Wrapf(ErrUnknownShardStrategy, "strategy %d", t)
+	}
+}
+
+type murmur3Strategy struct{}
+
+func (murmur3Strategy) Shard(key []byte, shardNum uint32) (common.ShardID, error) {
+	id, err := ShardID(key, shardNum)
+	return common.ShardID(id), err
+}
+
+type xxhashStrategy struct{}
+
+func (xxhashStrategy) Shard(key []byte, shardNum uint32) (common.ShardID, error) {
+	if shardNum < 1 {
+		return 0, errors.Wrap(ErrMalformedElement, "shardNum must be greater than 0")
+	}
+	return common.ShardID(xxhash.Sum64(key) % uint64(shardNum)), nil
+}
+
+// rendezvousStrategy implements highest-random-weight hashing: the shard
+// that owns key is whichever shard id maximizes hash(shardID || key).
+type rendezvousStrategy struct{}
+
+func (rendezvousStrategy) Shard(key []byte, shardNum uint32) (common.ShardID, error) {
+	if shardNum < 1 {
+		return 0, errors.Wrap(ErrMalformedElement, "shardNum must be greater than 0")
+	}
+	var best uint32
+	var bestWeight uint64
+	buf := make([]byte, 4+len(key))
+	for i := uint32(0); i < shardNum; i++ {
+		buf[0] = byte(i)
+		buf[1] = byte(i >> 8)
+		buf[2] = byte(i >> 16)
+		buf[3] = byte(i >> 24)
+		copy(buf[4:], key)
+		weight := xxhash.Sum64(buf)
+		if i == 0 || weight > bestWeight {
+			best, bestWeight = i, weight
+		}
+	}
+	return common.ShardID(best), nil
+}
+
+// jumpHashStrategy implements Lamping & Veach's jump consistent hash.
+type jumpHashStrategy struct{}
+
+func (jumpHashStrategy) Shard(key []byte, shardNum uint32) (common.ShardID, error) {
+	if shardNum < 1 {
+		return 0, errors.Wrap(ErrMalformedElement, "shardNum must be greater than 0")
+	}
+	return common.ShardID(jumpConsistentHash(xxhash.Sum64(key), int32(shardNum))), nil
+}
+
+func jumpConsistentHash(key uint64, numBuckets int32) int32 {
+	var b, j int64 = -1, 0
+	for j < int64(numBuckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int32(b)
+}
@@ -0,0 +1,80 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package partition
+
+import "testing"
+
+func TestJumpConsistentHash_SingleBucketAlwaysZero(t *testing.T) {
+	for _, key := range []uint64{0, 1, 12345, 1 << 63} {
+		if got := jumpConsistentHash(key, 1); got != 0 {
+			t.Fatalf("jumpConsistentHash(%d, 1) = %d, want 0", key, got)
+		}
+	}
+}
+
+func TestJumpConsistentHash_WithinRange(t *testing.T) {
+	for numBuckets := int32(1); numBuckets <= 64; numBuckets++ {
+		for _, key := range []uint64{0, 1, 42, 999999937} {
+			got := jumpConsistentHash(key, numBuckets)
+			if got < 0 || got >= numBuckets {
+				t.Fatalf("jumpConsistentHash(%d, %d) = %d, out of range", key, numBuckets, got)
+			}
+		}
+	}
+}
+
+// TestJumpConsistentHash_MonotonicOnGrowth checks the defining property of
+// jump consistent hashing (Lamping & Veach): growing the bucket count from
+// n to n+1 either keeps a key on its previous bucket or moves it to the
+// newly added bucket n, never anywhere else.
+func TestJumpConsistentHash_MonotonicOnGrowth(t *testing.T) {
+	for _, key := range []uint64{0, 7, 123456789, 42424242} {
+		for n := int32(1); n < 100; n++ {
+			before := jumpConsistentHash(key, n)
+			after := jumpConsistentHash(key, n+1)
+			if after != before && after != n {
+				t.Fatalf("key %d: growing buckets %d->%d moved bucket %d to %d, want %d or %d",
+					key, n, n+1, before, after, before, n)
+			}
+		}
+	}
+}
+
+func TestShardStrategies_WithinRange(t *testing.T) {
+	const shardNum = 16
+	key := []byte("service1.endpoint1")
+	for _, st := range []ShardStrategyType{ShardStrategyMurmur3, ShardStrategyXXHash, ShardStrategyRendezvous, ShardStrategyJumpHash} {
+		strategy, err := NewShardStrategy(st)
+		if err != nil {
+			t.Fatalf("NewShardStrategy(%v) error: %v", st, err)
+		}
+		id, err := strategy.Shard(key, shardNum)
+		if err != nil {
+			t.Fatalf("%v.Shard() error: %v", st, err)
+		}
+		if uint32(id) >= shardNum {
+			t.Fatalf("%v.Shard() = %d, want < %d", st, id, shardNum)
+		}
+	}
+}
+
+func TestNewShardStrategy_UnknownType(t *testing.T) {
+	if _, err := NewShardStrategy(ShardStrategyType(99)); err == nil {
+		t.Fatal("NewShardStrategy(99) error = nil, want ErrUnknownShardStrategy")
+	}
+}
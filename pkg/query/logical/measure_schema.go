@@ -0,0 +1,120 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package logical
+
+import (
+	apiv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/v1"
+	apischema "github.com/apache/skywalking-banyandb/api/schema"
+)
+
+// MeasureSchema is the Schema of a measure (metric) series. Measures have
+// no reserved trace id/state fields; instead they declare which tags form
+// the series entity, how fields are grouped into families, the roll-up
+// interval, and any TopN pre-aggregations defined over them.
+type MeasureSchema interface {
+	Schema
+	// EntityTagNames lists, in order, the tags that make up the measure's
+	// series entity.
+	EntityTagNames() []string
+	// FieldFamilies lists the field families declared on the measure.
+	FieldFamilies() []*apiv1.FieldFamilySpec
+	// Interval is the roll-up/downsampling interval of the measure, e.g.
+	// "1m" or "1h".
+	Interval() string
+	// TopNSpecs lists the TopN pre-aggregations defined over the measure.
+	TopNSpecs() []*apiv1.TopNAggregation
+}
+
+var _ MeasureSchema = (*measureSchema)(nil)
+
+type measureSchema struct {
+	*baseSchema
+	measure *apiv1.Measure
+}
+
+// NewMeasureSchema builds a MeasureSchema over measure, using indexRule to
+// resolve which fields are indexed.
+func NewMeasureSchema(measure *apiv1.Measure, indexRule apischema.IndexRule) MeasureSchema {
+	s := &measureSchema{
+		baseSchema: &baseSchema{
+			indexRule: indexRule,
+			fieldMap:  make(map[string]*fieldSpec),
+			shard:     measure.GetShard(),
+			indexMap:  buildIndexMap(indexRule),
+		},
+		measure: measure,
+	}
+	s.registerFields()
+	return s
+}
+
+// registerFields populates fieldMap with every tag the measure can be
+// queried or grouped on: the entity tags that make up its series key live
+// in tag family 0, ordered as declared on Entity; each field family's
+// fields follow in their own tag family (familyIdx+1), ordered as declared
+// within the family. This mirrors how the write path lays out
+// TagFamilyForWrite, so CreateRef and BuildTagFilter resolve entity tags
+// and metric fields uniformly. Entity tags carry no declared FieldSpec of
+// their own (they are opaque identity components), so a bare FieldSpec
+// holding just the name is synthesized for them.
+func (s *measureSchema) registerFields() {
+	i := 0
+	for tagOffset, name := range s.EntityTagNames() {
+		s.RegisterField(name, i, 0, tagOffset, &apiv1.FieldSpec{Name: name})
+		i++
+	}
+	for familyIdx, family := range s.FieldFamilies() {
+		for tagOffset, field := range family.GetFields() {
+			s.RegisterField(field.GetName(), i, familyIdx+1, tagOffset, field)
+			i++
+		}
+	}
+}
+
+func (s *measureSchema) EntityTagNames() []string {
+	return s.measure.GetEntity().GetTagNames()
+}
+
+func (s *measureSchema) FieldFamilies() []*apiv1.FieldFamilySpec {
+	return s.measure.GetFieldFamilies()
+}
+
+func (s *measureSchema) Interval() string {
+	return s.measure.GetInterval()
+}
+
+func (s *measureSchema) TopNSpecs() []*apiv1.TopNAggregation {
+	return s.measure.GetTopN()
+}
+
+func (s *measureSchema) Equal(s2 Schema) bool {
+	if other, ok := s2.(*measureSchema); ok {
+		return s.equal(other.baseSchema)
+	}
+	return false
+}
+
+func (s *measureSchema) Map(refs ...*FieldRef) Schema {
+	if len(refs) == 0 {
+		return nil
+	}
+	return &measureSchema{
+		baseSchema: s.mapped(refs...),
+		measure:    s.measure,
+	}
+}
@@ -23,92 +23,173 @@ import (
 
 	apiv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/v1"
 	apischema "github.com/apache/skywalking-banyandb/api/schema"
+	"github.com/apache/skywalking-banyandb/pkg/partition"
 )
 
+// Schema is the model-agnostic surface every data model (trace, measure,
+// and eventually log) exposes to the analyzer. TraceSchema and
+// MeasureSchema embed it with model-specific accessors, so analyzers,
+// CreateRef, Map, and IndexDefined work uniformly and planners built on top
+// of logical can target either data model without type switches.
 type Schema interface {
 	IndexDefined(string) (bool, *apiv1.IndexObject)
 	FieldSubscript(string) (bool, int)
+	// IndexType reports which index kind (tree, inverted, LSM, ...) is
+	// declared for field.
+	IndexType(field string) apiv1.IndexType
+	// SupportedMatchers reports which binary operators the index declared
+	// for field can evaluate directly.
+	SupportedMatchers(field string) []apiv1.PairQuery_BinaryOp
 	FieldDefined(string) bool
 	CreateRef(names ...string) ([]*FieldRef, error)
 	Map(refs ...*FieldRef) Schema
 	Equal(Schema) bool
 	ShardNumber() uint32
-	TraceIDFieldName() string
-	TraceStateFieldName() string
+	// ShardStrategy returns the hash strategy and entity-key prefix length
+	// declared on the schema's Shard spec, ready to hand to
+	// partition.EntityLocator on both the write and query paths.
+	ShardStrategy() (partition.ShardStrategyType, int)
+	// EntityLocator builds a partition.EntityLocator over entityTagNames,
+	// resolving each name to its (family, tag) offset via CreateRef and
+	// instantiating the schema's declared ShardStrategy, so the write path
+	// (series locating) and the query path (shard resolution) agree on how
+	// an entity key is built and hashed.
+	EntityLocator(entityTagNames ...string) (partition.EntityLocator, error)
+	// BuildTagFilter compiles the given criteria into a reusable TagFilter
+	// that evaluates raw TagFamilyForWrite tuples directly, without going
+	// through the index path. Multiple criteria are ANDed together.
+	BuildTagFilter(criteria ...*apiv1.Criteria) (TagFilter, error)
 }
 
 type fieldSpec struct {
-	Idx  int
-	spec *apiv1.FieldSpec
+	Idx          int
+	FamilyOffset int
+	TagOffset    int
+	spec         *apiv1.FieldSpec
 }
 
 func (fs *fieldSpec) Equal(other *fieldSpec) bool {
 	return fs.Idx == other.Idx && fs.spec.GetType() == other.spec.GetType() && fs.spec.GetName() == other.spec.GetName()
 }
 
-var _ Schema = (*schema)(nil)
-
-type schema struct {
-	traceSeries *apiv1.TraceSeries
-	indexRule   apischema.IndexRule
-	fieldMap    map[string]*fieldSpec
-}
-
-func (s *schema) TraceIDFieldName() string {
-	return s.traceSeries.GetReservedFieldsMap().GetTraceId()
+// indexEntry is the precomputed, per-field result of a lookup into the
+// index rule: which IndexObject declares the field and at what subscript.
+type indexEntry struct {
+	object    *apiv1.IndexObject
+	subscript int
 }
 
-func (s *schema) TraceStateFieldName() string {
-	return s.traceSeries.GetReservedFieldsMap().GetState().GetField()
+// baseSchema implements the model-agnostic parts of Schema. TraceSchema and
+// MeasureSchema implementations embed it and add their own reserved-field
+// and model-specific accessors on top.
+type baseSchema struct {
+	indexRule apischema.IndexRule
+	fieldMap  map[string]*fieldSpec
+	shard     *apiv1.Shard
+	indexMap  map[string]indexEntry
 }
 
-// IndexDefined checks whether the field given is indexed
-func (s *schema) IndexDefined(field string) (bool, *apiv1.IndexObject) {
-	idxRule := s.indexRule.Spec
-	for _, indexObj := range idxRule.GetObjects() {
+// buildIndexMap precomputes fieldName -> (indexObject, subscript) once at
+// schema construction, so IndexDefined and FieldSubscript no longer need to
+// linearly rescan indexRule.Spec.GetObjects() on every analyzer call. A
+// field declared in more than one IndexObject keeps resolving to the first
+// one, matching the linear scan IndexDefined/FieldSubscript used to do.
+func buildIndexMap(indexRule apischema.IndexRule) map[string]indexEntry {
+	idxRule := indexRule.Spec
+	objects := idxRule.GetObjects()
+	m := make(map[string]indexEntry, len(objects))
+	for i, indexObj := range objects {
 		for _, fieldName := range indexObj.GetFields() {
-			if field == fieldName {
-				return true, indexObj
+			if _, ok := m[fieldName]; ok {
+				continue
 			}
+			m[fieldName] = indexEntry{object: indexObj, subscript: i}
 		}
 	}
-	return false, nil
+	return m
 }
 
-func (s *schema) FieldSubscript(field string) (bool, int) {
-	idxRule := s.indexRule.Spec
-	for i, indexObj := range idxRule.GetObjects() {
-		for _, fieldName := range indexObj.GetFields() {
-			if field == fieldName {
-				return true, i
-			}
-		}
+// IndexDefined checks whether the field given is indexed
+func (s *baseSchema) IndexDefined(field string) (bool, *apiv1.IndexObject) {
+	entry, ok := s.indexMap[field]
+	if !ok {
+		return false, nil
 	}
-	return false, -1
+	return true, entry.object
 }
 
-func (s *schema) Equal(s2 Schema) bool {
-	if other, ok := s2.(*schema); ok {
-		return cmp.Equal(other.fieldMap, s.fieldMap)
+func (s *baseSchema) FieldSubscript(field string) (bool, int) {
+	entry, ok := s.indexMap[field]
+	if !ok {
+		return false, -1
 	}
-	return false
+	return true, entry.subscript
+}
+
+// IndexType reports which index kind (tree, inverted, LSM, ...) is declared
+// for field, so the planner can choose between range scans and equality
+// lookups when both are declared on the same field.
+func (s *baseSchema) IndexType(field string) apiv1.IndexType {
+	entry, ok := s.indexMap[field]
+	if !ok {
+		return apiv1.IndexType_INDEX_TYPE_UNSPECIFIED
+	}
+	return entry.object.GetIndexType()
 }
 
-func (s *schema) RegisterField(name string, i int, spec *apiv1.FieldSpec) {
+// SupportedMatchers reports which binary operators can be evaluated by the
+// index declared for field, so the analyzer can decide at plan time whether
+// a predicate can be pushed down to the index or must fall back to a
+// TagFilter.
+func (s *baseSchema) SupportedMatchers(field string) []apiv1.PairQuery_BinaryOp {
+	entry, ok := s.indexMap[field]
+	if !ok {
+		return nil
+	}
+	switch entry.object.GetIndexType() {
+	case apiv1.IndexType_INDEX_TYPE_TREE:
+		return []apiv1.PairQuery_BinaryOp{
+			apiv1.PairQuery_BINARY_OP_EQ, apiv1.PairQuery_BINARY_OP_NE,
+			apiv1.PairQuery_BINARY_OP_GT, apiv1.PairQuery_BINARY_OP_GE,
+			apiv1.PairQuery_BINARY_OP_LT, apiv1.PairQuery_BINARY_OP_LE,
+		}
+	case apiv1.IndexType_INDEX_TYPE_INVERTED, apiv1.IndexType_INDEX_TYPE_LSM:
+		return []apiv1.PairQuery_BinaryOp{
+			apiv1.PairQuery_BINARY_OP_EQ, apiv1.PairQuery_BINARY_OP_NE,
+			apiv1.PairQuery_BINARY_OP_HAVING, apiv1.PairQuery_BINARY_OP_NOT_HAVING,
+		}
+	default:
+		return nil
+	}
+}
+
+func (s *baseSchema) equal(other *baseSchema) bool {
+	return cmp.Equal(other.fieldMap, s.fieldMap)
+}
+
+// RegisterField registers a field together with its real location within
+// the wire-format TagFamilyForWrite tuple: familyOffset identifies which
+// TagFamilyForWrite carries it, tagOffset its position within that family.
+// Callers must pass the field's actual (familyOffset, tagOffset); a schema
+// can span more than one tag family (field families, multi-family traces),
+// so the global registration index i alone cannot locate it on the wire.
+func (s *baseSchema) RegisterField(name string, i, familyOffset, tagOffset int, spec *apiv1.FieldSpec) {
 	s.fieldMap[name] = &fieldSpec{
-		Idx:  i,
-		spec: spec,
+		Idx:          i,
+		FamilyOffset: familyOffset,
+		TagOffset:    tagOffset,
+		spec:         spec,
 	}
 }
 
-func (s *schema) FieldDefined(name string) bool {
+func (s *baseSchema) FieldDefined(name string) bool {
 	if _, ok := s.fieldMap[name]; ok {
 		return true
 	}
 	return false
 }
 
-func (s *schema) CreateRef(names ...string) ([]*FieldRef, error) {
+func (s *baseSchema) CreateRef(names ...string) ([]*FieldRef, error) {
 	var fieldRefs []*FieldRef
 	for _, name := range names {
 		if fs, ok := s.fieldMap[name]; ok {
@@ -120,14 +201,12 @@ func (s *schema) CreateRef(names ...string) ([]*FieldRef, error) {
 	return fieldRefs, nil
 }
 
-func (s *schema) Map(refs ...*FieldRef) Schema {
-	if len(refs) == 0 {
-		return nil
-	}
-	newS := &schema{
-		traceSeries: s.traceSeries,
-		indexRule:   s.indexRule,
-		fieldMap:    make(map[string]*fieldSpec),
+func (s *baseSchema) mapped(refs ...*FieldRef) *baseSchema {
+	newS := &baseSchema{
+		indexRule: s.indexRule,
+		shard:     s.shard,
+		indexMap:  s.indexMap,
+		fieldMap:  make(map[string]*fieldSpec),
 	}
 	for _, ref := range refs {
 		newS.fieldMap[ref.name] = ref.Spec
@@ -135,6 +214,108 @@ func (s *schema) Map(refs ...*FieldRef) Schema {
 	return newS
 }
 
-func (s *schema) ShardNumber() uint32 {
-	return s.traceSeries.Shard.Number
-}
\ No newline at end of file
+func (s *baseSchema) ShardNumber() uint32 {
+	return s.shard.GetNumber()
+}
+
+// ShardStrategy translates the declarative strategy on the schema's Shard
+// spec into the partition package's runtime type, defaulting to murmur3
+// (the historical behavior) when the schema does not declare one.
+func (s *baseSchema) ShardStrategy() (partition.ShardStrategyType, int) {
+	prefixLen := int(s.shard.GetPrefixTagsCount())
+	switch s.shard.GetStrategy() {
+	case apiv1.Shard_STRATEGY_XXHASH:
+		return partition.ShardStrategyXXHash, prefixLen
+	case apiv1.Shard_STRATEGY_RENDEZVOUS:
+		return partition.ShardStrategyRendezvous, prefixLen
+	case apiv1.Shard_STRATEGY_JUMP_HASH:
+		return partition.ShardStrategyJumpHash, prefixLen
+	default:
+		return partition.ShardStrategyMurmur3, prefixLen
+	}
+}
+
+// EntityLocator resolves entityTagNames against fieldMap and instantiates
+// the ShardStrategy declared on the schema's Shard spec, so every caller
+// builds its partition.EntityLocator the same way instead of each
+// re-deriving tag offsets and re-interpreting ShardStrategyType by hand.
+func (s *baseSchema) EntityLocator(entityTagNames ...string) (partition.EntityLocator, error) {
+	refs, err := s.CreateRef(entityTagNames...)
+	if err != nil {
+		return partition.EntityLocator{}, err
+	}
+	strategyType, prefixLen := s.ShardStrategy()
+	strategy, err := partition.NewShardStrategy(strategyType)
+	if err != nil {
+		return partition.EntityLocator{}, err
+	}
+	tagLocators := make([]partition.TagLocator, len(refs))
+	for i, ref := range refs {
+		tagLocators[i] = partition.TagLocator{FamilyOffset: ref.Spec.FamilyOffset, TagOffset: ref.Spec.TagOffset}
+	}
+	return partition.EntityLocator{Strategy: strategy, TagLocators: tagLocators, PrefixLen: prefixLen}, nil
+}
+
+// BuildTagFilter compiles criteria into a TagFilter. Each criteria tree is
+// resolved independently and the resulting filters are ANDed together, so
+// callers may either pass a single pre-combined criteria or several
+// independent ones.
+func (s *baseSchema) BuildTagFilter(criteria ...*apiv1.Criteria) (TagFilter, error) {
+	if len(criteria) == 0 {
+		return matchAllFilter{}, nil
+	}
+	filters := make([]TagFilter, 0, len(criteria))
+	for _, c := range criteria {
+		f, err := s.buildTagFilter(c)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+	return newCompositeFilter(opAnd, filters...), nil
+}
+
+func (s *baseSchema) buildTagFilter(criteria *apiv1.Criteria) (TagFilter, error) {
+	if cond := criteria.GetCondition(); cond != nil {
+		return s.buildLeafFilter(cond)
+	}
+	switch criteria.GetOp() {
+	case apiv1.Criteria_LO_NOT:
+		left, err := s.buildTagFilter(criteria.GetLeft())
+		if err != nil {
+			return nil, err
+		}
+		return newCompositeFilter(opNot, left), nil
+	case apiv1.Criteria_LO_AND, apiv1.Criteria_LO_OR:
+		left, err := s.buildTagFilter(criteria.GetLeft())
+		if err != nil {
+			return nil, err
+		}
+		right, err := s.buildTagFilter(criteria.GetRight())
+		if err != nil {
+			return nil, err
+		}
+		op := opAnd
+		if criteria.GetOp() == apiv1.Criteria_LO_OR {
+			op = opOr
+		}
+		return newCompositeFilter(op, left, right), nil
+	default:
+		return nil, errors.Wrap(ErrUnsupportedCriteria, criteria.GetOp().String())
+	}
+}
+
+func (s *baseSchema) buildLeafFilter(cond *apiv1.PairQuery) (TagFilter, error) {
+	fs, ok := s.fieldMap[cond.GetName()]
+	if !ok {
+		return nil, errors.Wrap(ErrFieldNotDefined, cond.GetName())
+	}
+	return &leafFilter{
+		ref: &FieldRef{cond.GetName(), fs},
+		op:  cond.GetOp(),
+		val: cond.GetValue(),
+	}, nil
+}
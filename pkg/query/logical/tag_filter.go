@@ -0,0 +1,210 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package logical
+
+import (
+	"github.com/pkg/errors"
+
+	modelv2 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v2"
+	apiv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/v1"
+)
+
+var (
+	// ErrUnsupportedCriteria is returned when a criteria tree uses a
+	// logical operator BuildTagFilter does not know how to compile.
+	ErrUnsupportedCriteria = errors.New("unsupported criteria")
+	// ErrUnsupportedTagType is returned when a leaf filter is evaluated
+	// against a tag value whose type it was not compiled for.
+	ErrUnsupportedTagType = errors.New("unsupported tag type")
+)
+
+// TagFilter is a compiled, reusable predicate over raw TagFamilyForWrite
+// tuples. It lets the query executor and streaming write-side processors
+// (e.g. TopN pre-aggregation, downsampling) share a single compiled filter
+// instead of each re-parsing and re-evaluating criteria independently.
+type TagFilter interface {
+	// Match reports whether tagFamilies satisfies the filter.
+	Match(tagFamilies []*modelv2.TagFamilyForWrite) (bool, error)
+	// Fields returns the fields this filter reads, so callers can project
+	// them ahead of time.
+	Fields() []*FieldRef
+}
+
+type matchAllFilter struct{}
+
+func (matchAllFilter) Match([]*modelv2.TagFamilyForWrite) (bool, error) { return true, nil }
+
+func (matchAllFilter) Fields() []*FieldRef { return nil }
+
+type logicalOp int
+
+const (
+	opAnd logicalOp = iota
+	opOr
+	opNot
+)
+
+type compositeFilter struct {
+	op       logicalOp
+	children []TagFilter
+}
+
+func newCompositeFilter(op logicalOp, children ...TagFilter) TagFilter {
+	return &compositeFilter{op: op, children: children}
+}
+
+func (c *compositeFilter) Match(tagFamilies []*modelv2.TagFamilyForWrite) (bool, error) {
+	switch c.op {
+	case opAnd:
+		for _, child := range c.children {
+			ok, err := child.Match(tagFamilies)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	case opOr:
+		for _, child := range c.children {
+			ok, err := child.Match(tagFamilies)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				return true, nil
+			}
+		}
+		return false, nil
+	case opNot:
+		ok, err := c.children[0].Match(tagFamilies)
+		if err != nil {
+			return false, err
+		}
+		return !ok, nil
+	}
+	return false, errors.Wrap(ErrUnsupportedCriteria, "unknown logical operator")
+}
+
+func (c *compositeFilter) Fields() []*FieldRef {
+	var refs []*FieldRef
+	for _, child := range c.children {
+		refs = append(refs, child.Fields()...)
+	}
+	return refs
+}
+
+// leafFilter evaluates a single binary comparison against one tag. It fetches
+// the tag directly from its known (family, offset) location instead of
+// scanning the index.
+type leafFilter struct {
+	ref *FieldRef
+	op  apiv1.PairQuery_BinaryOp
+	val *modelv2.TagValue
+}
+
+func (l *leafFilter) Fields() []*FieldRef { return []*FieldRef{l.ref} }
+
+func (l *leafFilter) Match(tagFamilies []*modelv2.TagFamilyForWrite) (bool, error) {
+	tag, err := tagAt(tagFamilies, l.ref.Spec.FamilyOffset, l.ref.Spec.TagOffset)
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case tag.GetInt() != nil && l.val.GetInt() != nil:
+		return matchInt(tag.GetInt().GetValue(), l.op, l.val.GetInt().GetValue())
+	case tag.GetStr() != nil && l.val.GetStr() != nil:
+		return matchStr(tag.GetStr().GetValue(), l.op, l.val.GetStr().GetValue())
+	case tag.GetStrArray() != nil:
+		return matchStrArray(tag.GetStrArray().GetValue(), l.op, l.val)
+	case tag.GetBinaryData() != nil:
+		return matchBinary(tag.GetBinaryData(), l.op, l.val.GetBinaryData())
+	default:
+		return false, errors.Wrapf(ErrUnsupportedTagType, "field %s", l.ref.name)
+	}
+}
+
+func tagAt(tagFamilies []*modelv2.TagFamilyForWrite, familyOffset, tagOffset int) (*modelv2.TagValue, error) {
+	if familyOffset >= len(tagFamilies) {
+		return nil, errors.Wrap(ErrUnsupportedCriteria, "tag family offset out of range")
+	}
+	tags := tagFamilies[familyOffset].GetTags()
+	if tagOffset >= len(tags) {
+		return nil, errors.Wrap(ErrUnsupportedCriteria, "tag offset out of range")
+	}
+	return tags[tagOffset], nil
+}
+
+func matchInt(got int64, op apiv1.PairQuery_BinaryOp, want int64) (bool, error) {
+	switch op {
+	case apiv1.PairQuery_BINARY_OP_EQ:
+		return got == want, nil
+	case apiv1.PairQuery_BINARY_OP_NE:
+		return got != want, nil
+	case apiv1.PairQuery_BINARY_OP_GT:
+		return got > want, nil
+	case apiv1.PairQuery_BINARY_OP_GE:
+		return got >= want, nil
+	case apiv1.PairQuery_BINARY_OP_LT:
+		return got < want, nil
+	case apiv1.PairQuery_BINARY_OP_LE:
+		return got <= want, nil
+	default:
+		return false, errors.Wrap(ErrUnsupportedCriteria, op.String())
+	}
+}
+
+func matchStr(got string, op apiv1.PairQuery_BinaryOp, want string) (bool, error) {
+	switch op {
+	case apiv1.PairQuery_BINARY_OP_EQ:
+		return got == want, nil
+	case apiv1.PairQuery_BINARY_OP_NE:
+		return got != want, nil
+	default:
+		return false, errors.Wrap(ErrUnsupportedCriteria, op.String())
+	}
+}
+
+func matchStrArray(got []string, op apiv1.PairQuery_BinaryOp, want *modelv2.TagValue) (bool, error) {
+	switch op {
+	case apiv1.PairQuery_BINARY_OP_HAVING, apiv1.PairQuery_BINARY_OP_NOT_HAVING:
+		target := want.GetStr().GetValue()
+		found := false
+		for _, v := range got {
+			if v == target {
+				found = true
+				break
+			}
+		}
+		if op == apiv1.PairQuery_BINARY_OP_NOT_HAVING {
+			return !found, nil
+		}
+		return found, nil
+	default:
+		return false, errors.Wrap(ErrUnsupportedCriteria, op.String())
+	}
+}
+
+func matchBinary(got []byte, op apiv1.PairQuery_BinaryOp, want []byte) (bool, error) {
+	switch op {
+	case apiv1.PairQuery_BINARY_OP_EQ:
+		return string(got) == string(want), nil
+	case apiv1.PairQuery_BINARY_OP_NE:
+		return string(got) != string(want), nil
+	default:
+		return false, errors.Wrap(ErrUnsupportedCriteria, op.String())
+	}
+}
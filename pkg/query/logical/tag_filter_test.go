@@ -0,0 +1,229 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package logical
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+
+	modelv2 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v2"
+	apiv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/v1"
+)
+
+// stubFilter is a TagFilter whose result and call count are fixed, so
+// compositeFilter's short-circuit behavior can be observed directly
+// without constructing real criteria/tag trees.
+type stubFilter struct {
+	result bool
+	err    error
+	calls  *int
+}
+
+func (s stubFilter) Match([]*modelv2.TagFamilyForWrite) (bool, error) {
+	if s.calls != nil {
+		*s.calls++
+	}
+	return s.result, s.err
+}
+
+func (s stubFilter) Fields() []*FieldRef { return nil }
+
+func TestCompositeFilter_And_ShortCircuitsOnFirstFalse(t *testing.T) {
+	calls := 0
+	f := newCompositeFilter(opAnd,
+		stubFilter{result: false, calls: &calls},
+		stubFilter{result: true, calls: &calls},
+	)
+	ok, err := f.Match(nil)
+	if err != nil {
+		t.Fatalf("Match() error = %v, want nil", err)
+	}
+	if ok {
+		t.Fatal("Match() = true, want false")
+	}
+	if calls != 1 {
+		t.Fatalf("evaluated %d children, want 1 (short-circuit after first false)", calls)
+	}
+}
+
+func TestCompositeFilter_And_AllTrue(t *testing.T) {
+	f := newCompositeFilter(opAnd,
+		stubFilter{result: true},
+		stubFilter{result: true},
+	)
+	ok, err := f.Match(nil)
+	if err != nil || !ok {
+		t.Fatalf("Match() = (%v, %v), want (true, nil)", ok, err)
+	}
+}
+
+func TestCompositeFilter_Or_ShortCircuitsOnFirstTrue(t *testing.T) {
+	calls := 0
+	f := newCompositeFilter(opOr,
+		stubFilter{result: true, calls: &calls},
+		stubFilter{result: false, calls: &calls},
+	)
+	ok, err := f.Match(nil)
+	if err != nil {
+		t.Fatalf("Match() error = %v, want nil", err)
+	}
+	if !ok {
+		t.Fatal("Match() = false, want true")
+	}
+	if calls != 1 {
+		t.Fatalf("evaluated %d children, want 1 (short-circuit after first true)", calls)
+	}
+}
+
+func TestCompositeFilter_Or_AllFalse(t *testing.T) {
+	f := newCompositeFilter(opOr,
+		stubFilter{result: false},
+		stubFilter{result: false},
+	)
+	ok, err := f.Match(nil)
+	if err != nil || ok {
+		t.Fatalf("Match() = (%v, %v), want (false, nil)", ok, err)
+	}
+}
+
+func TestCompositeFilter_Not_NegatesChild(t *testing.T) {
+	f := newCompositeFilter(opNot, stubFilter{result: true})
+	ok, err := f.Match(nil)
+	if err != nil || ok {
+		t.Fatalf("Match() = (%v, %v), want (false, nil)", ok, err)
+	}
+
+	f = newCompositeFilter(opNot, stubFilter{result: false})
+	ok, err = f.Match(nil)
+	if err != nil || !ok {
+		t.Fatalf("Match() = (%v, %v), want (true, nil)", ok, err)
+	}
+}
+
+func TestCompositeFilter_And_PropagatesChildError(t *testing.T) {
+	wantErr := errors.New("boom")
+	f := newCompositeFilter(opAnd, stubFilter{result: true, err: wantErr})
+	_, err := f.Match(nil)
+	if errors.Cause(err) != wantErr {
+		t.Fatalf("Match() error = %v, want %v", err, wantErr)
+	}
+}
+
+func intTagValue(v int64) *modelv2.TagValue {
+	return &modelv2.TagValue{Value: &modelv2.TagValue_Int{Int: &modelv2.Int{Value: v}}}
+}
+
+func strTagValue(v string) *modelv2.TagValue {
+	return &modelv2.TagValue{Value: &modelv2.TagValue_Str{Str: &modelv2.Str{Value: v}}}
+}
+
+func strArrayTagValue(v ...string) *modelv2.TagValue {
+	return &modelv2.TagValue{Value: &modelv2.TagValue_StrArray{StrArray: &modelv2.StrArray{Value: v}}}
+}
+
+func binaryTagValue(v []byte) *modelv2.TagValue {
+	return &modelv2.TagValue{Value: &modelv2.TagValue_BinaryData{BinaryData: v}}
+}
+
+// tagFamiliesWith puts tag at family 0, offset 0, the location refFor's
+// leafFilter is built to read.
+func tagFamiliesWith(tag *modelv2.TagValue) []*modelv2.TagFamilyForWrite {
+	return []*modelv2.TagFamilyForWrite{{Tags: []*modelv2.TagValue{tag}}}
+}
+
+func refFor(name string) *FieldRef {
+	return &FieldRef{name, &fieldSpec{FamilyOffset: 0, TagOffset: 0, spec: &apiv1.FieldSpec{Name: name}}}
+}
+
+func TestLeafFilter_MatchInt(t *testing.T) {
+	cases := []struct {
+		op   apiv1.PairQuery_BinaryOp
+		got  int64
+		want int64
+		ok   bool
+	}{
+		{apiv1.PairQuery_BINARY_OP_EQ, 5, 5, true},
+		{apiv1.PairQuery_BINARY_OP_EQ, 5, 6, false},
+		{apiv1.PairQuery_BINARY_OP_NE, 5, 6, true},
+		{apiv1.PairQuery_BINARY_OP_GT, 6, 5, true},
+		{apiv1.PairQuery_BINARY_OP_GE, 5, 5, true},
+		{apiv1.PairQuery_BINARY_OP_LT, 4, 5, true},
+		{apiv1.PairQuery_BINARY_OP_LE, 5, 5, true},
+	}
+	for _, c := range cases {
+		l := &leafFilter{ref: refFor("n"), op: c.op, val: intTagValue(c.want)}
+		ok, err := l.Match(tagFamiliesWith(intTagValue(c.got)))
+		if err != nil {
+			t.Fatalf("op %v: Match() error = %v", c.op, err)
+		}
+		if ok != c.ok {
+			t.Fatalf("op %v: Match(%d, %d) = %v, want %v", c.op, c.got, c.want, ok, c.ok)
+		}
+	}
+}
+
+func TestLeafFilter_MatchStr(t *testing.T) {
+	l := &leafFilter{ref: refFor("s"), op: apiv1.PairQuery_BINARY_OP_EQ, val: strTagValue("svc")}
+	ok, err := l.Match(tagFamiliesWith(strTagValue("svc")))
+	if err != nil || !ok {
+		t.Fatalf("Match() = (%v, %v), want (true, nil)", ok, err)
+	}
+
+	l = &leafFilter{ref: refFor("s"), op: apiv1.PairQuery_BINARY_OP_NE, val: strTagValue("svc")}
+	ok, err = l.Match(tagFamiliesWith(strTagValue("other")))
+	if err != nil || !ok {
+		t.Fatalf("Match() = (%v, %v), want (true, nil)", ok, err)
+	}
+}
+
+func TestLeafFilter_MatchStrArray(t *testing.T) {
+	l := &leafFilter{ref: refFor("tags"), op: apiv1.PairQuery_BINARY_OP_HAVING, val: strTagValue("b")}
+	ok, err := l.Match(tagFamiliesWith(strArrayTagValue("a", "b", "c")))
+	if err != nil || !ok {
+		t.Fatalf("HAVING: Match() = (%v, %v), want (true, nil)", ok, err)
+	}
+
+	l = &leafFilter{ref: refFor("tags"), op: apiv1.PairQuery_BINARY_OP_NOT_HAVING, val: strTagValue("z")}
+	ok, err = l.Match(tagFamiliesWith(strArrayTagValue("a", "b", "c")))
+	if err != nil || !ok {
+		t.Fatalf("NOT_HAVING: Match() = (%v, %v), want (true, nil)", ok, err)
+	}
+}
+
+func TestLeafFilter_MatchBinary(t *testing.T) {
+	l := &leafFilter{ref: refFor("id"), op: apiv1.PairQuery_BINARY_OP_EQ, val: binaryTagValue([]byte{1, 2, 3})}
+	ok, err := l.Match(tagFamiliesWith(binaryTagValue([]byte{1, 2, 3})))
+	if err != nil || !ok {
+		t.Fatalf("Match() = (%v, %v), want (true, nil)", ok, err)
+	}
+
+	l = &leafFilter{ref: refFor("id"), op: apiv1.PairQuery_BINARY_OP_NE, val: binaryTagValue([]byte{1, 2, 3})}
+	ok, err = l.Match(tagFamiliesWith(binaryTagValue([]byte{9})))
+	if err != nil || !ok {
+		t.Fatalf("Match() = (%v, %v), want (true, nil)", ok, err)
+	}
+}
+
+func TestLeafFilter_Match_TypeMismatchIsUnsupported(t *testing.T) {
+	l := &leafFilter{ref: refFor("n"), op: apiv1.PairQuery_BINARY_OP_EQ, val: intTagValue(5)}
+	_, err := l.Match(tagFamiliesWith(strTagValue("5")))
+	if errors.Cause(err) != ErrUnsupportedTagType {
+		t.Fatalf("Match() error = %v, want ErrUnsupportedTagType", err)
+	}
+}
@@ -0,0 +1,78 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package logical
+
+import (
+	apiv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/v1"
+	apischema "github.com/apache/skywalking-banyandb/api/schema"
+)
+
+// TraceSchema is the Schema of a trace series: in addition to the
+// model-agnostic accessors, it knows which fields carry the reserved
+// trace id and trace state.
+type TraceSchema interface {
+	Schema
+	TraceIDFieldName() string
+	TraceStateFieldName() string
+}
+
+var _ TraceSchema = (*traceSchema)(nil)
+
+type traceSchema struct {
+	*baseSchema
+	traceSeries *apiv1.TraceSeries
+}
+
+// NewTraceSchema builds a TraceSchema over traceSeries, using indexRule to
+// resolve which fields are indexed.
+func NewTraceSchema(traceSeries *apiv1.TraceSeries, indexRule apischema.IndexRule) TraceSchema {
+	return &traceSchema{
+		baseSchema: &baseSchema{
+			indexRule: indexRule,
+			fieldMap:  make(map[string]*fieldSpec),
+			shard:     traceSeries.GetShard(),
+			indexMap:  buildIndexMap(indexRule),
+		},
+		traceSeries: traceSeries,
+	}
+}
+
+func (s *traceSchema) TraceIDFieldName() string {
+	return s.traceSeries.GetReservedFieldsMap().GetTraceId()
+}
+
+func (s *traceSchema) TraceStateFieldName() string {
+	return s.traceSeries.GetReservedFieldsMap().GetState().GetField()
+}
+
+func (s *traceSchema) Equal(s2 Schema) bool {
+	if other, ok := s2.(*traceSchema); ok {
+		return s.equal(other.baseSchema)
+	}
+	return false
+}
+
+func (s *traceSchema) Map(refs ...*FieldRef) Schema {
+	if len(refs) == 0 {
+		return nil
+	}
+	return &traceSchema{
+		baseSchema:  s.mapped(refs...),
+		traceSeries: s.traceSeries,
+	}
+}
@@ -0,0 +1,100 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package topn
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// boundedHeap keeps the K most extreme Entry values seen via offer. When
+// ascending is false it keeps the K largest values using a min-heap
+// discipline (the root is the smallest of the retained K, and is evicted
+// first); when ascending is true it keeps the K smallest values using a
+// max-heap discipline. Either way offer and evict are O(log K), and the
+// heap never grows past size K.
+type boundedHeap struct {
+	k         int
+	ascending bool
+	data      []Entry
+}
+
+func newBoundedHeap(k int, ascending bool) *boundedHeap {
+	return &boundedHeap{k: k, ascending: ascending}
+}
+
+func (h *boundedHeap) Len() int { return len(h.data) }
+
+func (h *boundedHeap) Swap(i, j int) { h.data[i], h.data[j] = h.data[j], h.data[i] }
+
+func (h *boundedHeap) Less(i, j int) bool {
+	if h.ascending {
+		return h.data[i].Value > h.data[j].Value
+	}
+	return h.data[i].Value < h.data[j].Value
+}
+
+func (h *boundedHeap) Push(x interface{}) {
+	h.data = append(h.data, x.(Entry))
+}
+
+func (h *boundedHeap) Pop() interface{} {
+	old := h.data
+	n := len(old)
+	item := old[n-1]
+	h.data = old[:n-1]
+	return item
+}
+
+// offer considers a new entry for inclusion among the retained K, evicting
+// the current worst of the K when e is more extreme than it.
+func (h *boundedHeap) offer(e Entry) {
+	if h.k <= 0 {
+		return
+	}
+	if h.Len() < h.k {
+		heap.Push(h, e)
+		return
+	}
+	if h.evictableFor(e) {
+		h.data[0] = e
+		heap.Fix(h, 0)
+	}
+}
+
+func (h *boundedHeap) evictableFor(candidate Entry) bool {
+	root := h.data[0]
+	if h.ascending {
+		return candidate.Value < root.Value
+	}
+	return candidate.Value > root.Value
+}
+
+// drain returns the retained entries ordered by Value (ascending when the
+// heap keeps the smallest K, descending otherwise) and empties the heap.
+func (h *boundedHeap) drain() []Entry {
+	sort.Slice(h.data, func(i, j int) bool {
+		if h.ascending {
+			return h.data[i].Value < h.data[j].Value
+		}
+		return h.data[i].Value > h.data[j].Value
+	})
+	out := h.data
+	h.data = nil
+	return out
+}
@@ -0,0 +1,83 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package topn
+
+import "testing"
+
+func TestBoundedHeap_KeepsTopKDescending(t *testing.T) {
+	h := newBoundedHeap(3, false)
+	for _, v := range []float64{5, 1, 9, 3, 7, 2} {
+		h.offer(Entry{Value: v})
+	}
+	got := drainValues(h)
+	want := []float64{9, 7, 5}
+	assertValues(t, got, want)
+}
+
+func TestBoundedHeap_KeepsBottomKAscending(t *testing.T) {
+	h := newBoundedHeap(3, true)
+	for _, v := range []float64{5, 1, 9, 3, 7, 2} {
+		h.offer(Entry{Value: v})
+	}
+	got := drainValues(h)
+	want := []float64{1, 2, 3}
+	assertValues(t, got, want)
+}
+
+func TestBoundedHeap_EvictsWorstOfRetainedK(t *testing.T) {
+	h := newBoundedHeap(2, false)
+	h.offer(Entry{Value: 10})
+	h.offer(Entry{Value: 20})
+	// 5 is worse than both retained values (10, 20) and must not evict.
+	h.offer(Entry{Value: 5})
+	// 30 beats the current worst retained value (10) and must evict it.
+	h.offer(Entry{Value: 30})
+	got := drainValues(h)
+	want := []float64{30, 20}
+	assertValues(t, got, want)
+}
+
+func TestBoundedHeap_ZeroKNeverRetains(t *testing.T) {
+	h := newBoundedHeap(0, false)
+	h.offer(Entry{Value: 1})
+	h.offer(Entry{Value: 2})
+	if got := h.drain(); len(got) != 0 {
+		t.Fatalf("drain() = %v, want empty", got)
+	}
+}
+
+func drainValues(h *boundedHeap) []float64 {
+	entries := h.drain()
+	values := make([]float64, len(entries))
+	for i, e := range entries {
+		values[i] = e.Value
+	}
+	return values
+}
+
+func assertValues(t *testing.T, got, want []float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
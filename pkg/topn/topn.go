@@ -0,0 +1,205 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package topn implements a heap-based Top-K executor for measure TopN
+// pre-aggregation. It locates each write with a partition.EntityLocator,
+// rolls its value up per group of a configurable subset of entity tags,
+// and on flush bounds each shard's group totals to the K most (or least)
+// extreme ones, so that hot-service/hot-endpoint TopN queries can be
+// answered from a compact secondary series instead of scanning raw data.
+package topn
+
+import (
+	"bytes"
+
+	"github.com/pkg/errors"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	modelv2 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v2"
+	"github.com/apache/skywalking-banyandb/pkg/partition"
+	pbv2 "github.com/apache/skywalking-banyandb/pkg/pb/v2"
+	"github.com/apache/skywalking-banyandb/pkg/query/logical"
+)
+
+// ErrUnsupportedValueType is returned when ValueRef resolves to a tag whose
+// type cannot be reduced to a float64.
+var ErrUnsupportedValueType = errors.New("unsupported value type for topn")
+
+// Entry is a single rolled-up (entity, value, timestamp) triple produced by
+// the Top-K executor.
+type Entry struct {
+	GroupKey  string
+	Value     float64
+	Timestamp int64
+}
+
+// Sink receives the bounded Top-K entries a shard flushes, e.g. to persist
+// them as a compact secondary series.
+type Sink interface {
+	Flush(shard common.ShardID, entries []Entry) error
+}
+
+// Executor maintains, per shard, the running per-GroupKey aggregate of
+// writes located by Locator, keyed on ValueRef. Filter is applied before a
+// write is aggregated, so only writes matching the TopN's predicate are
+// considered. The bounded heap of the K most (Ascending = false) or least
+// (Ascending = true) extreme groups is only computed at Flush, over the
+// rolled-up per-group totals, not over individual writes.
+type Executor struct {
+	Locator   partition.EntityLocator
+	Filter    logical.TagFilter
+	GroupBy   []*logical.FieldRef
+	ValueRef  *logical.FieldRef
+	Sink      Sink
+	shards    map[common.ShardID]map[string]*Entry
+	K         int
+	Ascending bool
+}
+
+// NewExecutor builds an Executor that keeps, per shard, the top K groups
+// (Ascending = false) or bottom K groups (Ascending = true) by aggregated
+// Value.
+func NewExecutor(locator partition.EntityLocator, filter logical.TagFilter, groupBy []*logical.FieldRef, valueRef *logical.FieldRef, k int, ascending bool, sink Sink) *Executor {
+	return &Executor{
+		Locator:   locator,
+		Filter:    filter,
+		GroupBy:   groupBy,
+		ValueRef:  valueRef,
+		K:         k,
+		Ascending: ascending,
+		Sink:      sink,
+		shards:    make(map[common.ShardID]map[string]*Entry),
+	}
+}
+
+// Add pre-filters a single write and rolls it up into its shard's running
+// per-GroupKey total, so repeated writes for the same group within the
+// window add to one Entry instead of each consuming a slot in the
+// eventual bounded heap.
+func (e *Executor) Add(tagFamilies []*modelv2.TagFamilyForWrite, shardNum uint32, timestamp int64) error {
+	if e.Filter != nil {
+		ok, err := e.Filter.Match(tagFamilies)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+	}
+	_, shardID, err := e.Locator.Locate(tagFamilies, shardNum)
+	if err != nil {
+		return err
+	}
+	groupKey, err := groupKeyOf(tagFamilies, e.GroupBy)
+	if err != nil {
+		return err
+	}
+	value, err := valueOf(tagFamilies, e.ValueRef)
+	if err != nil {
+		return err
+	}
+	groups, ok := e.shards[shardID]
+	if !ok {
+		groups = make(map[string]*Entry)
+		e.shards[shardID] = groups
+	}
+	if entry, ok := groups[groupKey]; ok {
+		entry.Value += value
+		entry.Timestamp = timestamp
+	} else {
+		groups[groupKey] = &Entry{GroupKey: groupKey, Value: value, Timestamp: timestamp}
+	}
+	return nil
+}
+
+// Flush bounds each shard's aggregated groups down to the top/bottom K
+// through a boundedHeap, sends them through Sink, and resets the shards for
+// the next window.
+func (e *Executor) Flush() error {
+	for shardID, groups := range e.shards {
+		h := newBoundedHeap(e.K, e.Ascending)
+		for _, entry := range groups {
+			h.offer(*entry)
+		}
+		if err := e.Sink.Flush(shardID, h.drain()); err != nil {
+			return err
+		}
+	}
+	e.shards = make(map[common.ShardID]map[string]*Entry)
+	return nil
+}
+
+// MergeGlobalTopN assembles a global Top-K from each shard's already
+// bounded Top-K. GroupBy is typically a subset of the entity the default
+// shard strategy hashes on (e.g. group by service while sharding on
+// service+endpoint), so the same GroupKey commonly shows up as a separate
+// partial sum on more than one shard; merging those partials as
+// independent entries would under-count the group and could drop a
+// genuinely hot one that fell below K on every individual shard. Sum
+// partials sharing a GroupKey first, then bound the merged totals down to
+// the global top/bottom K.
+func MergeGlobalTopN(perShard map[common.ShardID][]Entry, k int, ascending bool) []Entry {
+	merged := make(map[string]*Entry)
+	for _, entries := range perShard {
+		for _, entry := range entries {
+			if existing, ok := merged[entry.GroupKey]; ok {
+				existing.Value += entry.Value
+				if entry.Timestamp > existing.Timestamp {
+					existing.Timestamp = entry.Timestamp
+				}
+			} else {
+				e := entry
+				merged[entry.GroupKey] = &e
+			}
+		}
+	}
+	h := newBoundedHeap(k, ascending)
+	for _, entry := range merged {
+		h.offer(*entry)
+	}
+	return h.drain()
+}
+
+func groupKeyOf(tagFamilies []*modelv2.TagFamilyForWrite, groupBy []*logical.FieldRef) (string, error) {
+	parts := make([][]byte, 0, len(groupBy))
+	for _, ref := range groupBy {
+		tag, err := partition.GetTagByOffset(tagFamilies, ref.Spec.FamilyOffset, ref.Spec.TagOffset)
+		if err != nil {
+			return "", err
+		}
+		marshaled, err := pbv2.MarshalIndexFieldValue(tag)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, marshaled)
+	}
+	return string(bytes.Join(parts, []byte{0})), nil
+}
+
+func valueOf(tagFamilies []*modelv2.TagFamilyForWrite, ref *logical.FieldRef) (float64, error) {
+	tag, err := partition.GetTagByOffset(tagFamilies, ref.Spec.FamilyOffset, ref.Spec.TagOffset)
+	if err != nil {
+		return 0, err
+	}
+	if i := tag.GetInt(); i != nil {
+		return float64(i.GetValue()), nil
+	}
+	if f := tag.GetFloat(); f != nil {
+		return f.GetValue(), nil
+	}
+	return 0, errors.Wrap(ErrUnsupportedValueType, "value field is not numeric")
+}
@@ -0,0 +1,211 @@
+// Licensed to Apache Software Foundation (ASF) under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Apache Software Foundation (ASF) licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package topn
+
+import (
+	"testing"
+
+	"github.com/apache/skywalking-banyandb/api/common"
+	modelv2 "github.com/apache/skywalking-banyandb/api/proto/banyandb/model/v2"
+	apiv1 "github.com/apache/skywalking-banyandb/api/proto/banyandb/v1"
+	apischema "github.com/apache/skywalking-banyandb/api/schema"
+	"github.com/apache/skywalking-banyandb/pkg/query/logical"
+)
+
+// testMeasureSchema builds a MeasureSchema whose entity is (service,
+// endpoint) and whose single field family carries a "latency" field, so
+// GroupBy can target just "service" -- a strict subset of the entity the
+// default shard strategy hashes on.
+func testMeasureSchema(t *testing.T) logical.MeasureSchema {
+	t.Helper()
+	measure := &apiv1.Measure{
+		Entity: &apiv1.Entity{TagNames: []string{"service", "endpoint"}},
+		FieldFamilies: []*apiv1.FieldFamilySpec{
+			{Fields: []*apiv1.FieldSpec{{Name: "latency"}}},
+		},
+	}
+	return logical.NewMeasureSchema(measure, apischema.IndexRule{})
+}
+
+func strTag(v string) *modelv2.TagValue {
+	return &modelv2.TagValue{Value: &modelv2.TagValue_Str{Str: &modelv2.Str{Value: v}}}
+}
+
+func intTag(v int64) *modelv2.TagValue {
+	return &modelv2.TagValue{Value: &modelv2.TagValue_Int{Int: &modelv2.Int{Value: v}}}
+}
+
+// write builds the two TagFamilyForWrite tuples testMeasureSchema's fields
+// are registered against: family 0 holds the entity tags (service,
+// endpoint), family 1 the latency field.
+func write(service, endpoint string, latency int64) []*modelv2.TagFamilyForWrite {
+	return []*modelv2.TagFamilyForWrite{
+		{Tags: []*modelv2.TagValue{strTag(service), strTag(endpoint)}},
+		{Tags: []*modelv2.TagValue{intTag(latency)}},
+	}
+}
+
+type capturingSink struct {
+	flushed map[common.ShardID][]Entry
+}
+
+func newCapturingSink() *capturingSink {
+	return &capturingSink{flushed: make(map[common.ShardID][]Entry)}
+}
+
+func (s *capturingSink) Flush(shard common.ShardID, entries []Entry) error {
+	s.flushed[shard] = entries
+	return nil
+}
+
+func TestExecutor_Add_AggregatesRepeatsByGroupKeyOnSameShard(t *testing.T) {
+	schema := testMeasureSchema(t)
+	locator, err := schema.EntityLocator("service", "endpoint")
+	if err != nil {
+		t.Fatalf("EntityLocator() error = %v", err)
+	}
+	groupBy, err := schema.CreateRef("service")
+	if err != nil {
+		t.Fatalf("CreateRef(service) error = %v", err)
+	}
+	valueRefs, err := schema.CreateRef("latency")
+	if err != nil {
+		t.Fatalf("CreateRef(latency) error = %v", err)
+	}
+
+	sink := newCapturingSink()
+	exec := NewExecutor(locator, nil, groupBy, valueRefs[0], 10, false, sink)
+
+	// Same (service, endpoint) entity, so both writes land on the same
+	// shard: Add must sum them into a single Entry instead of keeping two.
+	if err := exec.Add(write("svc-a", "ep-1", 10), 4, 1000); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := exec.Add(write("svc-a", "ep-1", 15), 4, 1001); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := exec.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	total := 0
+	var got float64
+	for _, entries := range sink.flushed {
+		for _, e := range entries {
+			total++
+			got = e.Value
+		}
+	}
+	if total != 1 {
+		t.Fatalf("flushed %d entries, want 1 (repeats on the same shard must aggregate)", total)
+	}
+	if got != 25 {
+		t.Fatalf("aggregated Value = %v, want 25", got)
+	}
+}
+
+func TestMergeGlobalTopN_SumsPartialsAcrossShardsByGroupKey(t *testing.T) {
+	schema := testMeasureSchema(t)
+	locator, err := schema.EntityLocator("service", "endpoint")
+	if err != nil {
+		t.Fatalf("EntityLocator() error = %v", err)
+	}
+	groupBy, err := schema.CreateRef("service")
+	if err != nil {
+		t.Fatalf("CreateRef(service) error = %v", err)
+	}
+	valueRefs, err := schema.CreateRef("latency")
+	if err != nil {
+		t.Fatalf("CreateRef(latency) error = %v", err)
+	}
+
+	sink := newCapturingSink()
+	// K is larger than the number of distinct groups that can land on any
+	// one shard, so no shard-local eviction ever drops a partial: this
+	// isolates MergeGlobalTopN's cross-shard summing as the only thing
+	// under test. With enough distinct endpoints spread across shards,
+	// svc-a's total is split into several per-shard partial sums.
+	exec := NewExecutor(locator, nil, groupBy, valueRefs[0], 10, false, sink)
+
+	svcATotal := int64(0)
+	for i := 0; i < 8; i++ {
+		endpoint := string(rune('a' + i))
+		latency := int64(10 + i)
+		if err := exec.Add(write("svc-a", endpoint, latency), 8, int64(i)); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		svcATotal += latency
+	}
+	// svc-b is a single entity, landing on one shard with one partial sum.
+	svcBLatency := int64(50)
+	if err := exec.Add(write("svc-b", "ep-only", svcBLatency), 8, 100); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	if err := exec.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	merged := MergeGlobalTopN(sink.flushed, 2, false)
+	totals := make(map[string]float64, len(merged))
+	for _, e := range merged {
+		totals[e.GroupKey] += e.Value
+	}
+
+	svcAKey, err := groupKeyOf(write("svc-a", "ep-any", 0), groupBy)
+	if err != nil {
+		t.Fatalf("groupKeyOf(svc-a) error = %v", err)
+	}
+	svcBKey, err := groupKeyOf(write("svc-b", "ep-only", 0), groupBy)
+	if err != nil {
+		t.Fatalf("groupKeyOf(svc-b) error = %v", err)
+	}
+
+	if got := totals[svcAKey]; got != float64(svcATotal) {
+		t.Fatalf("merged svc-a total = %v, want %v (sum of every shard's partial)", got, svcATotal)
+	}
+	if got := totals[svcBKey]; got != float64(svcBLatency) {
+		t.Fatalf("merged svc-b total = %v, want %v", got, svcBLatency)
+	}
+}
+
+func TestGroupKeyOf_SameGroupTagsProduceSameKey(t *testing.T) {
+	schema := testMeasureSchema(t)
+	groupBy, err := schema.CreateRef("service")
+	if err != nil {
+		t.Fatalf("CreateRef(service) error = %v", err)
+	}
+	k1, err := groupKeyOf(write("svc-a", "ep-1", 1), groupBy)
+	if err != nil {
+		t.Fatalf("groupKeyOf() error = %v", err)
+	}
+	k2, err := groupKeyOf(write("svc-a", "ep-2", 2), groupBy)
+	if err != nil {
+		t.Fatalf("groupKeyOf() error = %v", err)
+	}
+	if k1 != k2 {
+		t.Fatalf("groupKeyOf() = %q, %q, want equal keys for the same GroupBy tag value", k1, k2)
+	}
+	k3, err := groupKeyOf(write("svc-b", "ep-1", 1), groupBy)
+	if err != nil {
+		t.Fatalf("groupKeyOf() error = %v", err)
+	}
+	if k1 == k3 {
+		t.Fatalf("groupKeyOf() = %q for both svc-a and svc-b, want distinct keys", k1)
+	}
+}